@@ -0,0 +1,240 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tlinden/i3ipc"
+)
+
+const DefaultSocketName = "swaycycle.sock"
+
+// socketPath returns the configured daemon socket path, defaulting
+// to $XDG_RUNTIME_DIR/swaycycle.sock (or the system tmp dir if that
+// variable is unset).
+func socketPath() string {
+	if Socket != "" {
+		return Socket
+	}
+
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, DefaultSocketName)
+}
+
+// dispatchToDaemon tries to hand the current invocation off to an
+// already running daemon by connecting to its socket and sending a
+// single line command. It returns handled=false with a nil error if
+// no daemon is listening, so the caller can fall back to doing a
+// one-shot tree walk itself.
+func dispatchToDaemon(cmd string) (handled bool, err error) {
+	conn, err := net.Dial("unix", socketPath())
+	if err != nil {
+		return false, nil
+	}
+	defer conn.Close()
+
+	if _, err := fmt.Fprintln(conn, cmd); err != nil {
+		return true, fmt.Errorf("failed to send command to daemon: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return true, fmt.Errorf("failed to read daemon reply: %w", err)
+	}
+
+	if reply = strings.TrimSpace(reply); reply != "ok" {
+		return true, fmt.Errorf("daemon: %s", reply)
+	}
+
+	return true, nil
+}
+
+// runDaemon keeps the sway IPC connection open, subscribes to
+// window, workspace and output events to incrementally maintain
+// Visibles and CurrentWorkspace, and serves next/prev commands on a
+// local unix socket, so that later invocations of swaycycle can
+// switch focus without paying for a fresh connection and tree walk.
+// Scoping (--output, --all-outputs, --app-id, --class, --exclude)
+// and config.toml excludes are read once, from the flags the daemon
+// itself was started with, and applied on every refresh: since
+// Visibles is shared daemon-wide state, the filter lives there too
+// rather than varying per client request.
+func runDaemon(ipc *i3ipc.I3ipc) error {
+	path := socketPath()
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	defer listener.Close()
+
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	filter, err := newFilter(config)
+	if err != nil {
+		return err
+	}
+
+	loadMru()
+
+	sway, err := ipc.GetTree()
+	if err != nil {
+		return err
+	}
+
+	if err := processJSON(sway, filter); err != nil {
+		return err
+	}
+
+	// seed the MRU list with whatever window is focused right now,
+	// since window::focus events only tell us about focus changes
+	// that happen after we start watching
+	if id := focusedWindowID(); id > 0 {
+		mruPromote(id)
+	}
+
+	events, err := ipc.Subscribe(i3ipc.EventWindow, i3ipc.EventWorkspace, i3ipc.EventOutput)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to sway events: %w", err)
+	}
+
+	go watchMruSignals()
+	go watchEvents(ipc, events, filter)
+
+	slog.Info("daemon ready", "socket", path)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return fmt.Errorf("accept on %s failed: %w", path, err)
+		}
+
+		go handleClient(conn, ipc)
+	}
+}
+
+// watchEvents keeps Visibles and CurrentWorkspace up to date as
+// sway reports window, workspace and output changes, re-applying
+// filter (the daemon's startup flags plus config.toml) on every
+// refresh. Re-fetching and re-walking the tree on every event is
+// simpler than patching each possible event shape by hand; the
+// daemon still wins over the one-shot mode because the IPC
+// connection and socket stay warm between keypresses.
+func watchEvents(ipc *i3ipc.I3ipc, events <-chan i3ipc.Event, filter *Filter) {
+	for event := range events {
+		if event.Type == i3ipc.EventWindow && event.Change == "focus" && event.Container != nil {
+			mruHandleFocusEvent(event.Container.Id)
+		}
+
+		sway, err := ipc.GetTree()
+		if err != nil {
+			slog.Warn("failed to refresh sway tree", "error", err)
+			continue
+		}
+
+		if err := processJSON(sway, filter); err != nil {
+			slog.Warn("failed to process sway tree", "error", err)
+		}
+	}
+}
+
+// handleClient reads a single line command ("next" or "prev",
+// optionally followed by "--mru" and/or "--no-switch") from conn,
+// acts on it using the daemon's current state and writes back "ok"
+// or an error message. These per-request flags reflect the invoking
+// client's own command line, not the daemon's: --no-switch in
+// particular must come from the request, since gating it on the
+// daemon's own startup flag would silently change the behaviour of
+// `swaycycle -n` depending on whether a daemon happens to be
+// running. Window scoping, by contrast, is fixed for the daemon's
+// lifetime (see runDaemon) rather than read from the command line
+// here.
+func handleClient(conn net.Conn, ipc *i3ipc.I3ipc) {
+	defer conn.Close()
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		fmt.Fprintln(conn, "empty command")
+		return
+	}
+
+	mru := false
+	noswitch := false
+
+	for _, field := range fields[1:] {
+		switch field {
+		case "--mru":
+			mru = true
+		case "--no-switch":
+			noswitch = true
+		}
+	}
+
+	var id int
+
+	switch fields[0] {
+	case "next", "prev":
+		if mru {
+			id = mruNext()
+		} else if fields[0] == "next" {
+			id = findNextWindow()
+		} else {
+			id = findPrevWindow()
+		}
+	default:
+		fmt.Fprintf(conn, "unknown command %q\n", fields[0])
+		return
+	}
+
+	if id > 0 && !noswitch {
+		if mru {
+			mruMarkSelfFocused(id)
+			mruScheduleSettle(id)
+		}
+
+		if err := switchFocus(id, ipc); err != nil {
+			fmt.Fprintln(conn, err)
+			return
+		}
+	}
+
+	fmt.Fprintln(conn, "ok")
+}
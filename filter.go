@@ -0,0 +1,156 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/tlinden/i3ipc"
+)
+
+// Filter narrows down which containers recurseNodes collects into
+// Visibles. A nil *Filter (and the zero value) matches the
+// pre-filter behaviour: every window, no output/workspace scoping
+// beyond what the caller already applies.
+type Filter struct {
+	Output        string         // restrict to this output, or "focused"
+	AllOutputs    bool           // ignore output/workspace scoping entirely
+	AppID         string         // glob matched against node.AppID
+	Class         string         // glob matched against node.WindowProperties.Class
+	Exclude       *regexp.Regexp // matched against node.Name, if set
+	ExcludeAppIDs []string       // globs matched against node.AppID, any excludes
+}
+
+// Config is the subset of $XDG_CONFIG_HOME/swaycycle/config.toml we
+// understand: default excludes merged with any --exclude/--app-id
+// given on the command line. Exclude is matched against the window
+// name (like --exclude); ExcludeAppID is matched against app_id, so
+// things like picture-in-picture or notification popups can be
+// skipped by app_id rather than by their (often generic) title.
+type Config struct {
+	Exclude      []string `toml:"exclude"`
+	ExcludeAppID []string `toml:"exclude_app_id"`
+}
+
+// loadConfig reads $XDG_CONFIG_HOME/swaycycle/config.toml, if it
+// exists. A missing file is not an error, swaycycle works fine with
+// no configuration at all.
+func loadConfig() (*Config, error) {
+	path := configPath()
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+
+	var cfg Config
+
+	if _, err := toml.DecodeFile(path, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+func configPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(dir, "swaycycle", "config.toml")
+}
+
+// newFilter builds a Filter from the command-line flags, combining
+// --exclude with the default excludes from cfg rather than letting
+// one replace the other.
+func newFilter(cfg *Config) (*Filter, error) {
+	filter := &Filter{
+		Output:        OutputName,
+		AllOutputs:    AllOutputs,
+		AppID:         AppIDGlob,
+		Class:         ClassGlob,
+		ExcludeAppIDs: cfg.ExcludeAppID,
+	}
+
+	patterns := append([]string{}, cfg.Exclude...)
+	if ExcludeRe != "" {
+		patterns = append(patterns, ExcludeRe)
+	}
+
+	if len(patterns) == 0 {
+		return filter, nil
+	}
+
+	re, err := regexp.Compile(strings.Join(patterns, "|"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid --exclude pattern: %w", err)
+	}
+
+	filter.Exclude = re
+
+	return filter, nil
+}
+
+func (f *Filter) isAllOutputs() bool {
+	return f != nil && f.AllOutputs
+}
+
+func (f *Filter) wantsOutput() bool {
+	return f != nil && f.Output != ""
+}
+
+// matches reports whether node passes the filter's app-id, class
+// and exclude constraints. Output/workspace scoping is handled by
+// the caller, since it depends on tree position rather than node
+// attributes.
+func (f *Filter) matches(node *i3ipc.Node) bool {
+	if f == nil {
+		return true
+	}
+
+	if f.Exclude != nil && f.Exclude.MatchString(node.Name) {
+		return false
+	}
+
+	for _, glob := range f.ExcludeAppIDs {
+		if ok, err := path.Match(glob, node.AppID); err == nil && ok {
+			return false
+		}
+	}
+
+	if f.AppID != "" {
+		if ok, err := path.Match(f.AppID, node.AppID); err != nil || !ok {
+			return false
+		}
+	}
+
+	if f.Class != "" {
+		if ok, err := path.Match(f.Class, node.WindowProperties.Class); err != nil || !ok {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,122 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/tlinden/i3ipc"
+)
+
+func testNode(name, appid, class string) *i3ipc.Node {
+	return &i3ipc.Node{
+		Name:  name,
+		AppID: appid,
+		WindowProperties: i3ipc.WindowProperties{
+			Class: class,
+		},
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	cases := []struct {
+		name   string
+		filter *Filter
+		node   *i3ipc.Node
+		want   bool
+	}{
+		{
+			name:   "nil filter matches everything",
+			filter: nil,
+			node:   testNode("term", "foot", "foot"),
+			want:   true,
+		},
+		{
+			name:   "zero-value filter matches everything",
+			filter: &Filter{},
+			node:   testNode("term", "foot", "foot"),
+			want:   true,
+		},
+		{
+			name:   "app-id glob matches",
+			filter: &Filter{AppID: "fire*"},
+			node:   testNode("browser", "firefox", "Firefox"),
+			want:   true,
+		},
+		{
+			name:   "app-id glob rejects non-match",
+			filter: &Filter{AppID: "fire*"},
+			node:   testNode("term", "foot", "foot"),
+			want:   false,
+		},
+		{
+			name:   "class glob matches",
+			filter: &Filter{Class: "Fire*"},
+			node:   testNode("browser", "firefox", "Firefox"),
+			want:   true,
+		},
+		{
+			name:   "class glob rejects non-match",
+			filter: &Filter{Class: "Fire*"},
+			node:   testNode("term", "foot", "foot"),
+			want:   false,
+		},
+		{
+			name:   "exclude regex rejects match",
+			filter: &Filter{Exclude: regexp.MustCompile("^notif")},
+			node:   testNode("notification popup", "mako", "Mako"),
+			want:   false,
+		},
+		{
+			name:   "exclude regex allows non-match",
+			filter: &Filter{Exclude: regexp.MustCompile("^notif")},
+			node:   testNode("browser", "firefox", "Firefox"),
+			want:   true,
+		},
+		{
+			name:   "exclude app-id glob rejects match",
+			filter: &Filter{ExcludeAppIDs: []string{"pip-*"}},
+			node:   testNode("picture in picture", "pip-mpv", "mpv"),
+			want:   false,
+		},
+		{
+			name:   "exclude app-id glob allows non-match",
+			filter: &Filter{ExcludeAppIDs: []string{"pip-*"}},
+			node:   testNode("browser", "firefox", "Firefox"),
+			want:   true,
+		},
+		{
+			name: "app-id allow and exclude app-id combine",
+			filter: &Filter{
+				AppID:         "fire*",
+				ExcludeAppIDs: []string{"firefox-pip"},
+			},
+			node: testNode("picture in picture", "firefox-pip", "Firefox"),
+			want: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.filter.matches(c.node); got != c.want {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"log/slog"
 	"os"
 	"runtime/debug"
+	"sync"
 
 	"github.com/lmittmann/tint"
 	"github.com/mattn/go-isatty"
@@ -63,6 +64,16 @@ var (
 	Notswitch        = false
 	Showhelp         = false
 	Logfile          = ""
+	Daemon           = false
+	Socket           = ""
+	Mru              = false
+	OutputName       = ""
+	AllOutputs       = false
+	AppIDGlob        = ""
+	ClassGlob        = ""
+	ExcludeRe        = ""
+
+	stateMutex sync.Mutex
 )
 
 const Usage string = `This is swaycycle - cycle focus through all visible windows on a sway workspace.
@@ -75,6 +86,14 @@ Options:
   -d, --debug            enable debugging
   -D, --dump             dump the sway tree (needs -d as well)
   -l, --logfile string   write output to logfile
+      --daemon           keep running and serve next/prev over a socket
+      --socket string    daemon socket path (default $XDG_RUNTIME_DIR/swaycycle.sock)
+  -m, --mru              cycle in most-recently-focused (alt-tab) order, needs --daemon
+      --output string    cycle across all workspaces of this output, or "focused"
+      --all-outputs      cycle across every visible window on every output
+      --app-id string    restrict to windows whose app_id matches this glob
+      --class string     restrict to windows whose window class matches this glob
+      --exclude string   regex matched against node names to always skip
   -v, --version          show program version
 
 Copyleft (L) 2025 Thomas von Dein.
@@ -88,8 +107,16 @@ func main() {
 	flag.BoolVarP(&Notswitch, "no-switch", "n", false, "do not switch windows")
 	flag.BoolVarP(&Version, "version", "v", false, "show program version")
 	flag.BoolVarP(&Showhelp, "help", "h", Showhelp, "show help")
+	flag.BoolVar(&Daemon, "daemon", false, "keep running and serve next/prev over a socket")
+	flag.BoolVarP(&Mru, "mru", "m", false, "cycle in most-recently-focused (alt-tab) order, needs --daemon")
 
 	flag.StringVarP(&Logfile, "logfile", "l", "", "write output to logfile")
+	flag.StringVar(&Socket, "socket", "", "daemon socket path (default $XDG_RUNTIME_DIR/swaycycle.sock)")
+	flag.StringVar(&OutputName, "output", "", `cycle across all workspaces of this output, or "focused"`)
+	flag.BoolVar(&AllOutputs, "all-outputs", false, "cycle across every visible window on every output")
+	flag.StringVar(&AppIDGlob, "app-id", "", "restrict to windows whose app_id matches this glob")
+	flag.StringVar(&ClassGlob, "class", "", "restrict to windows whose window class matches this glob")
+	flag.StringVar(&ExcludeRe, "exclude", "", "regex matched against node names to always skip")
 	flag.Parse()
 
 	if Version {
@@ -114,6 +141,43 @@ func main() {
 		setupLogging(os.Stdout)
 	}
 
+	if !Daemon {
+		// if a daemon is already listening, let it do the work and
+		// skip connecting and walking the tree ourselves
+		cmd := "next"
+		if Previous {
+			cmd = "prev"
+		}
+
+		if Mru {
+			cmd += " --mru"
+		}
+
+		if Notswitch {
+			cmd += " --no-switch"
+		}
+
+		if Mru && Previous {
+			// mruNext only ever walks further back into history;
+			// there is no mruPrev, so warn rather than pretend -p
+			// does anything when a daemon handles the request
+			slog.Warn("--prev has no effect together with --mru when a daemon is running; it cycles forward through history instead")
+		}
+
+		handled, err := dispatchToDaemon(cmd)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if handled {
+			return
+		}
+
+		if Mru {
+			slog.Warn("--mru requires a running daemon; falling back to tree-order cycling")
+		}
+	}
+
 	// connect to sway unix socket
 	ipc := i3ipc.NewI3ipc()
 
@@ -123,13 +187,31 @@ func main() {
 	}
 	defer ipc.Close()
 
+	if Daemon {
+		if err := runDaemon(ipc); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	filter, err := newFilter(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	sway, err := ipc.GetTree()
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	// traverse the tree and find visible windows
-	if err := processJSON(sway); err != nil {
+	if err := processJSON(sway, filter); err != nil {
 		log.Fatalf("%s", err)
 	}
 
@@ -147,13 +229,17 @@ func main() {
 	}
 
 	if id > 0 && !Notswitch {
-		switchFocus(id, ipc)
+		if err := switchFocus(id, ipc); err != nil {
+			log.Fatal(err)
+		}
 	}
 }
 
-// get into the sway tree, determine current workspace and extract all
-// its visible windows, store them in the global var Visibles
-func processJSON(sway *i3ipc.Node) error {
+// get into the sway tree, determine current workspace and extract
+// all its visible windows matching filter, store them in the global
+// var Visibles. filter may be nil, in which case no windows are
+// excluded and scoping defaults to the current workspace.
+func processJSON(sway *i3ipc.Node, filter *Filter) error {
 	if !istype(sway, root) && len(sway.Nodes) == 0 {
 		return fmt.Errorf("Invalid or empty JSON structure")
 	}
@@ -162,14 +248,42 @@ func processJSON(sway *i3ipc.Node) error {
 		slog.Debug("processed sway tree", "sway", sway)
 	}
 
+	stateMutex.Lock()
+	Visibles = Visibles[:0]
+
 	for _, node := range sway.Nodes {
-		if node.Current_workspace != "" {
-			// this is an output node containing the current workspace
+		if !istype(node, output) {
+			continue
+		}
+
+		focused := node.Current_workspace != ""
+		if focused {
 			CurrentWorkspace = node.Current_workspace
-			recurseNodes(node.Nodes)
-			break
+		}
+
+		switch {
+		case filter.isAllOutputs():
+			recurseNodes(node.Nodes, "", filter)
+
+		case filter.wantsOutput() && filter.Output != "focused":
+			if node.Name == filter.Output {
+				recurseNodes(node.Nodes, "", filter)
+			}
+
+		case filter.wantsOutput():
+			// --output=focused: every workspace of the focused output
+			if focused {
+				recurseNodes(node.Nodes, "", filter)
+			}
+
+		default:
+			// unfiltered: current workspace on the focused output only
+			if focused {
+				recurseNodes(node.Nodes, CurrentWorkspace, filter)
+			}
 		}
 	}
+	stateMutex.Unlock()
 
 	slog.Debug("processed visible windows", "visibles", Visibles)
 
@@ -179,6 +293,9 @@ func processJSON(sway *i3ipc.Node) error {
 // find the next window after the  one with current focus. if the last
 // one has focus, return the first
 func findNextWindow() int {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
 	if len(Visibles) == 0 {
 		return 0
 	}
@@ -203,7 +320,25 @@ func findNextWindow() int {
 	return 0
 }
 
+// focusedWindowID returns the id of the currently focused window in
+// Visibles, or 0 if none is focused.
+func focusedWindowID() int {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
+	for _, node := range Visibles {
+		if node.Focused {
+			return node.Id
+		}
+	}
+
+	return 0
+}
+
 func findPrevWindow() int {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
 	vislen := len(Visibles)
 	if vislen == 0 {
 		return 0
@@ -220,12 +355,22 @@ func findPrevWindow() int {
 
 	return 0
 }
-// actually switch focus using a swaymsg command
+// actually switch focus using a swaymsg command. Returns an error
+// instead of exiting the process: in daemon mode a single failed
+// focus command (e.g. the container closed in the meantime) must
+// not take the whole daemon down.
 func switchFocus(id int, ipc *i3ipc.I3ipc) error {
 	responses, err := ipc.RunContainerCommand(id, "focus")
 	if err != nil {
-		log.Fatalf("failed to send focus command to container %d: %w (%s)",
-			id, responses[0].Error, err)
+		// most failure paths (send/read errors, bad JSON) return no
+		// responses at all, so responses[0] isn't safe to index here
+		msg := err.Error()
+		if len(responses) > 0 {
+			msg = responses[0].Error
+		}
+
+		return fmt.Errorf("failed to send focus command to container %d: %s (%w)",
+			id, msg, err)
 	}
 
 	slog.Info("switched focus", "con_id", id)
@@ -233,29 +378,39 @@ func switchFocus(id int, ipc *i3ipc.I3ipc) error {
 	return nil
 }
 
-// iterate recursively over given node list extracting visible windows
-func recurseNodes(nodes []*i3ipc.Node) {
+// iterate recursively over given node list extracting visible
+// windows matching filter. workspaceScope, if non-empty, restricts
+// descent to the workspace of that name; an empty workspaceScope
+// visits every workspace under nodes.
+func recurseNodes(nodes []*i3ipc.Node, workspaceScope string, filter *Filter) {
 	for _, node := range nodes {
 		// we handle nodes and floating_nodes identical
 		node.Nodes = append(node.Nodes, node.FloatingNodes...)
 
 		if istype(node, workspace) {
-			if node.Name == CurrentWorkspace {
-				recurseNodes(node.Nodes)
+			if workspaceScope != "" && node.Name != workspaceScope {
+				// ignore other workspaces
+				continue
+			}
+
+			recurseNodes(node.Nodes, workspaceScope, filter)
+
+			if workspaceScope != "" {
 				return
 			}
 
-			// ignore other workspaces
 			continue
 		}
 
 		// the first nodes seen are workspaces, so if we see a con
-		// node, we are already inside the current workspace
+		// node, we are already inside a workspace we care about
 		if (istype(node, con) || istype(node, floating)) &&
 			(node.Window > 0 || node.X11Window != "") {
-			Visibles = append(Visibles, node)
+			if filter.matches(node) {
+				Visibles = append(Visibles, node)
+			}
 		} else {
-			recurseNodes(node.Nodes)
+			recurseNodes(node.Nodes, workspaceScope, filter)
 		}
 	}
 }
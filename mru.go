@@ -0,0 +1,234 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// MruDebounce is how long a second --mru press may trail the
+	// previous one and still be considered part of the same walk
+	// back through history, instead of starting over at the front.
+	MruDebounce = 400 * time.Millisecond
+
+	MruFileName = "swaycycle-mru"
+)
+
+var (
+	mruMutex sync.Mutex
+	mruList  []int
+	mruLast  time.Time
+	mruDepth int
+
+	// mruSelfFocused is the id of the window we just asked sway to
+	// focus ourselves as part of an --mru walk. The resulting
+	// window::focus event must not be mistaken for the user having
+	// focused something new, or it would reset the walk on every
+	// single press (see mruHandleFocusEvent).
+	mruSelfFocused int
+
+	// mruSettleTimer fires once no further --mru press has arrived
+	// for MruDebounce, committing the walk by promoting the
+	// currently selected window to the front of mruList.
+	mruSettleTimer *time.Timer
+)
+
+// mruPath is where the MRU list is persisted across daemon
+// restarts: $XDG_RUNTIME_DIR (or the system tmp dir), the default
+// location of the daemon socket. --socket does not change it.
+func mruPath() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, MruFileName)
+}
+
+// mruPromote moves id to the front of the MRU list, inserting it if
+// it isn't already tracked. Used to seed the list with whatever is
+// focused when the daemon starts, where there is no walk in
+// progress to worry about resetting.
+func mruPromote(id int) {
+	mruMutex.Lock()
+	defer mruMutex.Unlock()
+
+	promoteLocked(id)
+}
+
+func promoteLocked(id int) {
+	for i, known := range mruList {
+		if known == id {
+			mruList = append(mruList[:i], mruList[i+1:]...)
+			break
+		}
+	}
+
+	mruList = append([]int{id}, mruList...)
+}
+
+// mruMarkSelfFocused records that handleClient is about to ask sway
+// to focus id itself, so the matching window::focus event can be
+// told apart from the user focusing something by other means.
+func mruMarkSelfFocused(id int) {
+	mruMutex.Lock()
+	mruSelfFocused = id
+	mruMutex.Unlock()
+}
+
+// mruHandleFocusEvent applies a window::focus event to the MRU
+// state. If id is the window we just focused ourselves as part of
+// an --mru walk, the event is consumed and otherwise ignored,
+// leaving the walk's depth/timing untouched so repeated presses
+// keep stepping further back into history instead of oscillating.
+// Any other focus change means the user moved focus some other way
+// (click, a non-mru next/prev, etc.), so id is promoted to the
+// front and the walk resets.
+func mruHandleFocusEvent(id int) {
+	mruMutex.Lock()
+	defer mruMutex.Unlock()
+
+	if id != 0 && id == mruSelfFocused {
+		mruSelfFocused = 0
+		return
+	}
+
+	promoteLocked(id)
+	mruDepth = 0
+	mruLast = time.Time{}
+}
+
+// mruScheduleSettle arranges for id to be promoted to the front of
+// mruList once MruDebounce has elapsed without a further --mru
+// press, mirroring alt-tab's "release commits the switch" semantics
+// even though every individual press already changed sway's actual
+// focus. Each call cancels any settle still pending from an earlier
+// press in the same walk.
+func mruScheduleSettle(id int) {
+	mruMutex.Lock()
+	defer mruMutex.Unlock()
+
+	if mruSettleTimer != nil {
+		mruSettleTimer.Stop()
+	}
+
+	mruSettleTimer = time.AfterFunc(MruDebounce, func() {
+		mruMutex.Lock()
+		defer mruMutex.Unlock()
+
+		promoteLocked(id)
+		mruDepth = 0
+		mruLast = time.Time{}
+	})
+}
+
+// mruNext returns the id to focus for an --mru invocation. Repeated
+// calls within MruDebounce of one another walk one step further
+// back into the history; once the debounce window elapses the walk
+// resets to the entry right behind the currently focused window.
+func mruNext() int {
+	mruMutex.Lock()
+	defer mruMutex.Unlock()
+
+	if len(mruList) < 2 {
+		if len(mruList) == 1 {
+			return mruList[0]
+		}
+
+		return 0
+	}
+
+	now := time.Now()
+	if !mruLast.IsZero() && now.Sub(mruLast) <= MruDebounce {
+		mruDepth++
+	} else {
+		mruDepth = 1
+	}
+	mruLast = now
+
+	if mruDepth >= len(mruList) {
+		mruDepth = len(mruList) - 1
+	}
+
+	return mruList[mruDepth]
+}
+
+// loadMru reads a previously persisted MRU list, if any, so
+// restarting the daemon does not lose alt-tab history.
+func loadMru() {
+	file, err := os.Open(mruPath())
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	mruMutex.Lock()
+	defer mruMutex.Unlock()
+
+	mruList = mruList[:0]
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		id, err := strconv.Atoi(scanner.Text())
+		if err != nil {
+			continue
+		}
+
+		mruList = append(mruList, id)
+	}
+}
+
+// saveMru persists the current MRU list, one container id per line.
+func saveMru() {
+	mruMutex.Lock()
+	defer mruMutex.Unlock()
+
+	file, err := os.Create(mruPath())
+	if err != nil {
+		slog.Warn("failed to persist MRU list", "error", err)
+		return
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, id := range mruList {
+		fmt.Fprintln(writer, id)
+	}
+	writer.Flush()
+}
+
+// watchMruSignals saves the MRU list to disk on SIGTERM, so that a
+// restarted daemon picks up where it left off.
+func watchMruSignals() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM)
+
+	<-sig
+	saveMru()
+	os.Exit(0)
+}
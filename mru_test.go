@@ -0,0 +1,185 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func resetMru(seed []int) {
+	mruMutex.Lock()
+	defer mruMutex.Unlock()
+
+	if mruSettleTimer != nil {
+		mruSettleTimer.Stop()
+		mruSettleTimer = nil
+	}
+
+	mruList = append([]int{}, seed...)
+	mruLast = time.Time{}
+	mruDepth = 0
+	mruSelfFocused = 0
+}
+
+func TestMruNext(t *testing.T) {
+	cases := []struct {
+		name    string
+		seed    []int
+		presses int
+		want    []int
+	}{
+		{
+			name:    "empty history returns nothing to focus",
+			seed:    nil,
+			presses: 1,
+			want:    []int{0},
+		},
+		{
+			name:    "single entry is always returned",
+			seed:    []int{42},
+			presses: 3,
+			want:    []int{42, 42, 42},
+		},
+		{
+			name:    "rapid presses walk back one step at a time",
+			seed:    []int{1, 2, 3, 4},
+			presses: 3,
+			want:    []int{2, 3, 4},
+		},
+		{
+			name:    "depth clamps at the oldest entry",
+			seed:    []int{1, 2},
+			presses: 4,
+			want:    []int{2, 2, 2, 2},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resetMru(c.seed)
+
+			for i := 0; i < c.presses; i++ {
+				if got := mruNext(); got != c.want[i] {
+					t.Errorf("press %d: got %d, want %d", i, got, c.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMruNextResetsAfterDebounceElapses(t *testing.T) {
+	resetMru([]int{1, 2, 3})
+
+	if got := mruNext(); got != 2 {
+		t.Fatalf("first press: got %d, want 2", got)
+	}
+
+	mruMutex.Lock()
+	mruLast = time.Now().Add(-2 * MruDebounce)
+	mruMutex.Unlock()
+
+	if got := mruNext(); got != 2 {
+		t.Fatalf("press after debounce elapsed: got %d, want 2 (walk should restart)", got)
+	}
+}
+
+// TestMruHandleFocusEventDuringWalkDoesNotResetDepth reproduces the
+// real runtime sequence: every --mru press calls switchFocus, which
+// makes sway report a window::focus event for the very window we
+// just asked it to focus, well inside MruDebounce. That self-induced
+// event must not be treated like an external focus change, or
+// repeated presses oscillate (2, 1, 2, 1, ...) instead of walking
+// further back into history (2, 3, 4, ...).
+func TestMruHandleFocusEventDuringWalkDoesNotResetDepth(t *testing.T) {
+	resetMru([]int{1, 2, 3, 4})
+
+	for i, want := range []int{2, 3, 4} {
+		got := mruNext()
+		if got != want {
+			t.Fatalf("press %d: got %d, want %d", i, got, want)
+		}
+
+		mruMarkSelfFocused(got)
+		mruHandleFocusEvent(got)
+	}
+}
+
+func TestMruHandleFocusEventResetsOnExternalFocus(t *testing.T) {
+	resetMru([]int{1, 2, 3})
+
+	if got := mruNext(); got != 2 {
+		t.Fatalf("first press: got %d, want 2", got)
+	}
+
+	// the user focused window 3 some other way, e.g. a mouse click,
+	// not something we requested ourselves
+	mruHandleFocusEvent(3)
+
+	mruMutex.Lock()
+	depth, last, front := mruDepth, mruLast, mruList[0]
+	mruMutex.Unlock()
+
+	if depth != 0 || !last.IsZero() {
+		t.Fatalf("external focus should reset the walk, got depth=%d last=%v", depth, last)
+	}
+
+	if front != 3 {
+		t.Fatalf("got front=%d, want 3", front)
+	}
+}
+
+func TestMruScheduleSettlePromotesAfterDebounce(t *testing.T) {
+	resetMru([]int{1, 2, 3})
+
+	mruScheduleSettle(3)
+	time.Sleep(MruDebounce + 50*time.Millisecond)
+
+	mruMutex.Lock()
+	front, depth := mruList[0], mruDepth
+	mruMutex.Unlock()
+
+	if front != 3 {
+		t.Fatalf("got front=%d, want 3", front)
+	}
+
+	if depth != 0 {
+		t.Fatalf("settle should reset walk depth, got %d", depth)
+	}
+}
+
+func TestMruPromoteMovesExistingEntryToFront(t *testing.T) {
+	resetMru([]int{1, 2, 3})
+
+	mruPromote(3)
+
+	mruMutex.Lock()
+	got := append([]int{}, mruList...)
+	mruMutex.Unlock()
+
+	want := []int{3, 1, 2}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
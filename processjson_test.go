@@ -0,0 +1,124 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/tlinden/i3ipc"
+)
+
+func testWindow(id int) *i3ipc.Node {
+	return &i3ipc.Node{
+		Type:   "con",
+		Id:     id,
+		Window: 1,
+	}
+}
+
+func testWorkspace(name string, wins ...*i3ipc.Node) *i3ipc.Node {
+	return &i3ipc.Node{
+		Type:  "workspace",
+		Name:  name,
+		Nodes: wins,
+	}
+}
+
+func testOutput(name, currentWorkspace string, workspaces ...*i3ipc.Node) *i3ipc.Node {
+	return &i3ipc.Node{
+		Type:              "output",
+		Name:              name,
+		Current_workspace: currentWorkspace,
+		Nodes:             workspaces,
+	}
+}
+
+func testTree() *i3ipc.Node {
+	return &i3ipc.Node{
+		Type: "root",
+		Nodes: []*i3ipc.Node{
+			testOutput("eDP-1", "ws1",
+				testWorkspace("ws1", testWindow(1), testWindow(2)),
+				testWorkspace("ws2", testWindow(3)),
+			),
+			testOutput("HDMI-1", "",
+				testWorkspace("ws3", testWindow(4)),
+			),
+		},
+	}
+}
+
+func visibleIDs(t *testing.T, sway *i3ipc.Node, filter *Filter) []int {
+	t.Helper()
+
+	if err := processJSON(sway, filter); err != nil {
+		t.Fatalf("processJSON: %v", err)
+	}
+
+	ids := make([]int, len(Visibles))
+	for i, node := range Visibles {
+		ids[i] = node.Id
+	}
+
+	return ids
+}
+
+func TestProcessJSONScoping(t *testing.T) {
+	cases := []struct {
+		name   string
+		filter *Filter
+		want   []int
+	}{
+		{
+			name:   "no filter: current workspace of the focused output only",
+			filter: nil,
+			want:   []int{1, 2},
+		},
+		{
+			name:   "all-outputs: every window on every output",
+			filter: &Filter{AllOutputs: true},
+			want:   []int{1, 2, 3, 4},
+		},
+		{
+			name:   "named output: every workspace of that output only",
+			filter: &Filter{Output: "HDMI-1"},
+			want:   []int{4},
+		},
+		{
+			name:   "output=focused: every workspace of the focused output",
+			filter: &Filter{Output: "focused"},
+			want:   []int{1, 2, 3},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := visibleIDs(t, testTree(), c.filter)
+
+			if len(got) != len(c.want) {
+				t.Fatalf("got %v, want %v", got, c.want)
+			}
+
+			for i := range c.want {
+				if got[i] != c.want[i] {
+					t.Fatalf("got %v, want %v", got, c.want)
+				}
+			}
+		})
+	}
+}